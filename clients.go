@@ -1,21 +1,54 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
+	"fmt"
 	"github.com/valyala/fasthttp/fasthttpproxy"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/valyala/fasthttp"
 	"golang.org/x/net/http2"
+	"golang.org/x/time/rate"
 )
 
 type client interface {
-	do() (code int, usTaken uint64, err error)
+	// do executes a single request and returns a RequestRecord acquired
+	// from requestRecordPool. Callers must releaseRequestRecord it once
+	// they are done reading it.
+	do() (rec *RequestRecord, err error)
+}
+
+// newClientFromURL picks a client backend based on opts.url's scheme,
+// so that grpc:// and ws(s):// targets transparently reuse the same
+// stats pipeline as the plain http(s) clients. grpcMethod/grpcPayload
+// and wsMessage are only consulted for their respective schemes.
+func newClientFromURL(
+	opts *clientOpts, useFastHTTP bool,
+	grpcMethod, grpcPayload string, wsMessage []byte,
+) (client, error) {
+	u, err := url.Parse(opts.url)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "grpc":
+		return newGRPCClient(opts, grpcMethod, grpcPayload)
+	case "ws", "wss":
+		return newWSClient(opts, wsMessage)
+	default:
+		if useFastHTTP {
+			return newFastHTTPClient(opts)
+		}
+		return newHTTPClient(opts), nil
+	}
 }
 
 type bodyStreamProducer func() (io.ReadCloser, error)
@@ -34,7 +67,22 @@ type clientOpts struct {
 	body    *string
 	bodProd bodyStreamProducer
 
+	// template, when set, overrides url/body (and adds to headers) on
+	// every request by re-rendering it from the request templates, so
+	// --body-file-list/randInt/uuid/seq/pick can vary each request's
+	// payload instead of repeating a static one.
+	template *requestTemplate
+
+	// validators are user-declared success criteria evaluated on top of
+	// the plain status code; a failing predicate turns the request into
+	// an assertionFailedCode entry in stats instead of a 2xx.
+	validators []predicate
+
 	bytesRead, bytesWritten *int64
+
+	// rateLimiter caps the aggregate request rate across all workers to
+	// the QPS requested via --rate. Nil means no cap is applied.
+	rateLimiter *rate.Limiter
 }
 
 type fasthttpClient struct {
@@ -45,9 +93,14 @@ type fasthttpClient struct {
 
 	body    *string
 	bodProd bodyStreamProducer
+
+	template   *requestTemplate
+	validators []predicate
+
+	rateLimiter *rate.Limiter
 }
 
-func newFastHTTPClient(opts *clientOpts) client {
+func newFastHTTPClient(opts *clientOpts) (client, error) {
 	var dial fasthttp.DialFunc
 
 	c := new(fasthttpClient)
@@ -87,12 +140,29 @@ func newFastHTTPClient(opts *clientOpts) client {
 	c.headers = headersToFastHTTPHeaders(opts.headers)
 	c.method, c.body = opts.method, opts.body
 	c.bodProd = opts.bodProd
-	return client(c)
+	c.template = opts.template
+	c.validators = opts.validators
+	c.rateLimiter = opts.rateLimiter
+
+	if opts.HTTP2 {
+		if h2err := configureFastHTTPH2(c.client); h2err != nil {
+			return nil, fmt.Errorf("bombardier: could not enable HTTP/2 for %q: %s", opts.url, h2err)
+		}
+	}
+
+	return client(c), nil
 }
 
-func (c *fasthttpClient) do() (
-	code int, usTaken uint64, err error,
-) {
+func (c *fasthttpClient) do() (rec *RequestRecord, err error) {
+	rec = acquireRequestRecord()
+
+	if c.rateLimiter != nil {
+		if werr := c.rateLimiter.Wait(context.Background()); werr != nil {
+			rec.ErrKind = errKindOther
+			return rec, werr
+		}
+	}
+
 	// prepare the request
 	req := fasthttp.AcquireRequest()
 	resp := fasthttp.AcquireResponse()
@@ -108,32 +178,69 @@ func (c *fasthttpClient) do() (
 	} else {
 		req.URI().SetScheme("http")
 	}
-	req.SetRequestURI(c.requestURI)
-	if c.body != nil {
-		req.SetBodyString(*c.body)
+
+	var streamedBody *countingReadCloser
+	if c.template != nil {
+		requestURI, headers, body, terr := c.template.render()
+		if terr != nil {
+			rec.ErrKind = errKindOther
+			return rec, terr
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		req.SetRequestURI(requestURI)
+		req.SetBodyString(body)
+		rec.WriteBytes = int64(len(body))
 	} else {
-		bs, bserr := c.bodProd()
-		if bserr != nil {
-			return 0, 0, bserr
+		req.SetRequestURI(c.requestURI)
+		if c.body != nil {
+			req.SetBodyString(*c.body)
+			rec.WriteBytes = int64(len(*c.body))
+		} else {
+			bs, bserr := c.bodProd()
+			if bserr != nil {
+				rec.ErrKind = errKindWrite
+				return rec, bserr
+			}
+			streamedBody = &countingReadCloser{ReadCloser: bs}
+			req.SetBodyStream(streamedBody, -1)
 		}
-		req.SetBodyStream(bs, -1)
 	}
 
 	// fire the request
 	start := time.Now()
 	err = c.client.Do(req, resp)
+	if streamedBody != nil {
+		rec.WriteBytes = atomic.LoadInt64(&streamedBody.n)
+	}
 	if err != nil {
-		code = -1
+		rec.Code = -1
+		rec.ErrKind = classifyErrKind(err)
 	} else {
-		code = resp.StatusCode()
+		rec.Code = resp.StatusCode()
+		rec.ReadBytes = int64(len(resp.Body()))
+		rec.BodySnippet = append(rec.BodySnippet, truncateBody(resp.Body())...)
+	}
+	rec.Cost = time.Since(start)
+
+	if err == nil && len(c.validators) > 0 {
+		view := responseView{body: resp.Body(), cost: rec.Cost, headers: make(map[string]string)}
+		resp.Header.VisitAll(func(k, v []byte) {
+			view.headers[string(k)] = string(v)
+		})
+		if verr := validateResponse(c.validators, view); verr != nil {
+			rec.Code = assertionFailedCode
+			rec.ErrKind = errKindAssertion
+			err = verr
+		}
 	}
-	usTaken = uint64(time.Since(start).Nanoseconds() / 1000)
 
 	// release resources
 	fasthttp.ReleaseRequest(req)
 	fasthttp.ReleaseResponse(resp)
 
-	return
+	return rec, err
 }
 
 type httpClient struct {
@@ -145,6 +252,11 @@ type httpClient struct {
 
 	body    *string
 	bodProd bodyStreamProducer
+
+	template   *requestTemplate
+	validators []predicate
+
+	rateLimiter *rate.Limiter
 }
 
 func newHTTPClient(opts *clientOpts) client {
@@ -183,6 +295,9 @@ func newHTTPClient(opts *clientOpts) client {
 
 	c.headers = headersToHTTPHeaders(opts.headers)
 	c.method, c.body, c.bodProd = opts.method, opts.body, opts.bodProd
+	c.template = opts.template
+	c.validators = opts.validators
+	c.rateLimiter = opts.rateLimiter
 
 	c.url, err = url.Parse(opts.url)
 	if err != nil {
@@ -193,50 +308,101 @@ func newHTTPClient(opts *clientOpts) client {
 	return client(c)
 }
 
-func (c *httpClient) do() (
-	code int, usTaken uint64, err error,
-) {
+func (c *httpClient) do() (rec *RequestRecord, err error) {
+	rec = acquireRequestRecord()
+
+	if c.rateLimiter != nil {
+		if werr := c.rateLimiter.Wait(context.Background()); werr != nil {
+			rec.ErrKind = errKindOther
+			return rec, werr
+		}
+	}
+
 	req := &http.Request{}
 
 	req.Header = c.headers
 	req.Method = c.method
 	req.URL = c.url
 
-	if host := req.Header.Get("Host"); host != "" {
-		req.Host = host
-	}
-
-	if c.body != nil {
+	var streamedBody *countingReadCloser
+	if c.template != nil {
+		rawURL, headers, body, terr := c.template.render()
+		if terr != nil {
+			rec.ErrKind = errKindOther
+			return rec, terr
+		}
+		if u, uerr := url.Parse(rawURL); uerr == nil {
+			req.URL = u
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		req.ContentLength = int64(len(body))
+		req.Body = ioutil.NopCloser(strings.NewReader(body))
+		rec.WriteBytes = req.ContentLength
+	} else if c.body != nil {
 		br := strings.NewReader(*c.body)
 		req.ContentLength = int64(len(*c.body))
 		req.Body = ioutil.NopCloser(br)
+		rec.WriteBytes = req.ContentLength
 	} else {
 		bs, bserr := c.bodProd()
 		if bserr != nil {
-			return 0, 0, bserr
+			rec.ErrKind = errKindWrite
+			return rec, bserr
 		}
-		req.Body = bs
+		streamedBody = &countingReadCloser{ReadCloser: bs}
+		req.Body = streamedBody
+	}
+
+	if host := req.Header.Get("Host"); host != "" {
+		req.Host = host
 	}
 
 	start := time.Now()
 	resp, err := c.client.Do(req)
+	if streamedBody != nil {
+		rec.WriteBytes = atomic.LoadInt64(&streamedBody.n)
+	}
 	if err != nil {
-		code = -1
+		rec.Code = -1
+		rec.ErrKind = classifyErrKind(err)
 	} else {
-		code = resp.StatusCode
+		rec.Code = resp.StatusCode
 
-		_, berr := io.Copy(ioutil.Discard, resp.Body)
+		bufCap := bodySnippetLen
+		if len(c.validators) > 0 {
+			bufCap = maxValidationBodyBytes
+		}
+		buf := &boundedBuffer{max: bufCap}
+		n, berr := io.Copy(io.MultiWriter(buf, ioutil.Discard), resp.Body)
+		rec.ReadBytes = n
+		rec.BodySnippet = append(rec.BodySnippet, truncateBody(buf.Bytes())...)
 		if berr != nil {
 			err = berr
+			rec.ErrKind = errKindRead
 		}
 
 		if cerr := resp.Body.Close(); cerr != nil {
 			err = cerr
+			rec.ErrKind = errKindRead
+		}
+
+		if err == nil && len(c.validators) > 0 {
+			view := responseView{body: buf.Bytes(), cost: time.Since(start), headers: make(map[string]string)}
+			for k := range resp.Header {
+				view.headers[k] = resp.Header.Get(k)
+			}
+			if verr := validateResponse(c.validators, view); verr != nil {
+				rec.Code = assertionFailedCode
+				rec.ErrKind = errKindAssertion
+				err = verr
+			}
 		}
 	}
-	usTaken = uint64(time.Since(start).Nanoseconds() / 1000)
+	rec.Cost = time.Since(start)
 
-	return
+	return rec, err
 }
 
 func headersToFastHTTPHeaders(h *headersList) *fasthttp.RequestHeader {
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/dgrr/http2"
+	"github.com/valyala/fasthttp"
+)
+
+// configureFastHTTPH2 upgrades host to speak HTTP/2, reusing the same
+// low-allocation fasthttp.HostClient the rest of fasthttpClient relies
+// on instead of falling back to net/http. It only applies to TLS
+// targets, since fasthttp has no h2c support and HTTP/2 negotiation
+// happens via ALPN during the TLS handshake.
+func configureFastHTTPH2(host *fasthttp.HostClient) error {
+	if !host.IsTLS {
+		return errors.New("HTTP/2 over the fasthttp client requires an https:// target")
+	}
+	return http2.ConfigureClient(host, http2.ClientOpts{})
+}
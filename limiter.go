@@ -0,0 +1,13 @@
+package main
+
+import "golang.org/x/time/rate"
+
+// newRateLimiter builds the token-bucket limiter shared by all worker
+// goroutines when --rate is set. qps <= 0 means no cap, and nil is
+// returned so callers can skip the Wait() call entirely in the hot path.
+func newRateLimiter(qps uint64) *rate.Limiter {
+	if qps == 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(qps), int(qps))
+}
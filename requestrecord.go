@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// bodySnippetLen bounds how much of a response body we retain on a
+// RequestRecord for debugging; we never buffer an entire body just to
+// look at it.
+const bodySnippetLen = 256
+
+// RequestRecord carries everything the aggregator needs to know about a
+// single request/response round-trip. Instances are recycled through
+// requestRecordPool instead of being allocated per request, since at
+// high QPS that allocation is a significant share of the hot path.
+type RequestRecord struct {
+	Cost    time.Duration
+	Code    int
+	ErrKind string
+
+	ReadBytes, WriteBytes int64
+
+	BodySnippet []byte
+}
+
+var requestRecordPool = sync.Pool{
+	New: func() interface{} { return new(RequestRecord) },
+}
+
+// acquireRequestRecord returns a zeroed RequestRecord from the pool.
+func acquireRequestRecord() *RequestRecord {
+	rec := requestRecordPool.Get().(*RequestRecord)
+	*rec = RequestRecord{BodySnippet: rec.BodySnippet[:0]}
+	return rec
+}
+
+// releaseRequestRecord returns rec to the pool. Callers must not touch
+// rec again afterwards.
+func releaseRequestRecord(rec *RequestRecord) {
+	requestRecordPool.Put(rec)
+}
+
+// errKind classification buckets, used both by the aggregator and in
+// JSON/TUI output.
+const (
+	errKindDNS     = "dns"
+	errKindConnect = "connect"
+	errKindTLS     = "tls"
+	errKindTimeout = "timeout"
+	errKindRead    = "read"
+	errKindWrite   = "write"
+	errKindOther   = "other"
+)
+
+// truncateBody returns at most bodySnippetLen bytes of b for attaching to
+// a RequestRecord; callers must copy it if they need it to outlive b.
+func truncateBody(b []byte) []byte {
+	if len(b) > bodySnippetLen {
+		return b[:bodySnippetLen]
+	}
+	return b
+}
+
+// boundedBuffer is an io.Writer that keeps only the first max bytes
+// written to it while still reporting every byte via Write's return
+// value, so it can sit in an io.MultiWriter alongside ioutil.Discard
+// without truncating the io.Copy byte count.
+type boundedBuffer struct {
+	buf []byte
+	max int
+}
+
+func (b *boundedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - len(b.buf); room > 0 {
+		if room > len(p) {
+			room = len(p)
+		}
+		b.buf = append(b.buf, p[:room]...)
+	}
+	return len(p), nil
+}
+
+func (b *boundedBuffer) Bytes() []byte { return b.buf }
+
+// countingReadCloser wraps a streamed request body so callers can learn
+// how many bytes were actually read out of it (and therefore written to
+// the wire) once the request completes, since a bodyStreamProducer's
+// length is unknown up front.
+type countingReadCloser struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	atomic.AddInt64(&c.n, int64(n))
+	return n, err
+}
+
+// classifyErrKind maps a transport-level error to a coarse taxonomy so
+// that users get more than an "any non-2xx / err" bucket in stats.
+func classifyErrKind(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return errKindDNS
+	}
+
+	var recordErr tls.RecordHeaderError
+	if errors.As(err, &recordErr) {
+		return errKindTLS
+	}
+
+	var unknownAuthErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certInvalidErr x509.CertificateInvalidError
+	switch {
+	case errors.As(err, &unknownAuthErr),
+		errors.As(err, &hostnameErr),
+		errors.As(err, &certInvalidErr),
+		strings.Contains(err.Error(), "x509:"):
+		return errKindTLS
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return errKindTimeout
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		switch opErr.Op {
+		case "dial":
+			return errKindConnect
+		case "read":
+			return errKindRead
+		case "write":
+			return errKindWrite
+		}
+	}
+
+	return errKindOther
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// varPattern matches {{varName}} placeholders inside a scenario step's
+// URL, headers or body.
+var varPattern = regexp.MustCompile(`{{\s*([\w.]+)\s*}}`)
+
+// scenarioStep is one request in a scenario, as loaded from the
+// --scenario file.
+type scenarioStep struct {
+	Name          string            `yaml:"name" json:"name"`
+	Method        string            `yaml:"method" json:"method"`
+	URL           string            `yaml:"url" json:"url"`
+	Headers       map[string]string `yaml:"headers" json:"headers"`
+	Body          string            `yaml:"body" json:"body"`
+	Expect        int               `yaml:"expectStatus" json:"expectStatus"`
+	ExtractJSON   map[string]string `yaml:"extractJSON" json:"extractJSON"`
+	ExtractRegexp map[string]string `yaml:"extractRegexp" json:"extractRegexp"`
+}
+
+// scenario is an ordered sequence of correlated requests executed once
+// per virtual user, loaded from a YAML or JSON file via --scenario.
+type scenario struct {
+	Steps []scenarioStep `yaml:"steps" json:"steps"`
+}
+
+// loadScenario reads and parses a scenario file. YAML is tried first and
+// falls back to JSON, mirroring how the rest of bombardier treats config
+// files loaded from disk.
+func loadScenario(path string) (*scenario, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := new(scenario)
+	if yerr := yaml.Unmarshal(raw, s); yerr == nil && len(s.Steps) > 0 {
+		return s, nil
+	}
+	if jerr := json.Unmarshal(raw, s); jerr != nil {
+		return nil, fmt.Errorf("bombardier: failed to parse scenario %q: %s", path, jerr)
+	}
+	return s, nil
+}
+
+// stepStats accumulates running per-step latency stats so they can be
+// reported separately from the scenario's overall cost, without keeping
+// a per-request sample around: a virtual user running for hours at high
+// QPS would otherwise grow such a sample slice without bound.
+type stepStats struct {
+	mu    sync.Mutex
+	count uint64
+	sum   time.Duration
+	min   time.Duration
+	max   time.Duration
+}
+
+func (s *stepStats) record(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.count == 0 || d < s.min {
+		s.min = d
+	}
+	if d > s.max {
+		s.max = d
+	}
+	s.count++
+	s.sum += d
+}
+
+// mean returns the average of every latency recorded so far.
+func (s *stepStats) mean() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.count == 0 {
+		return 0
+	}
+	return s.sum / time.Duration(s.count)
+}
+
+// scenarioClient drives one virtual user through a scenario: it issues
+// each step in order, substituting variables extracted from earlier
+// responses into later steps' URL, headers and body.
+type scenarioClient struct {
+	httpClient *http.Client
+	scenario   *scenario
+	stepStats  []*stepStats
+
+	vars map[string]string
+}
+
+func newScenarioClient(opts *clientOpts, sc *scenario) client {
+	c := &scenarioClient{
+		scenario:  sc,
+		stepStats: make([]*stepStats, len(sc.Steps)),
+		vars:      make(map[string]string),
+	}
+	for i := range c.stepStats {
+		c.stepStats[i] = new(stepStats)
+	}
+	c.httpClient = &http.Client{
+		Timeout: opts.timeout,
+		Transport: &http.Transport{
+			TLSClientConfig: opts.tlsConfig,
+			DialContext:     httpDialContextFunc(opts.bytesRead, opts.bytesWritten),
+		},
+	}
+	return client(c)
+}
+
+func (c *scenarioClient) render(tpl string) string {
+	return varPattern.ReplaceAllStringFunc(tpl, func(m string) string {
+		name := varPattern.FindStringSubmatch(m)[1]
+		return c.vars[name]
+	})
+}
+
+func (c *scenarioClient) do() (rec *RequestRecord, err error) {
+	rec = acquireRequestRecord()
+	start := time.Now()
+
+	// Variables extracted in a previous iteration must not leak into
+	// this one: a failed or skipped extraction should surface as a
+	// missing variable, not as stale data from an earlier run.
+	c.vars = make(map[string]string)
+
+	for i, step := range c.scenario.Steps {
+		stepStart := time.Now()
+		code, body, serr := c.runStep(step)
+		c.stepStats[i].record(time.Since(stepStart))
+
+		if serr != nil {
+			rec.Code = -1
+			rec.ErrKind = classifyErrKind(serr)
+			rec.Cost = time.Since(start)
+			return rec, serr
+		}
+		if step.Expect != 0 && code != step.Expect {
+			rec.Code = code
+			rec.ErrKind = errKindOther
+			rec.Cost = time.Since(start)
+			return rec, fmt.Errorf(
+				"bombardier: scenario step %q expected status %d, got %d",
+				step.Name, step.Expect, code,
+			)
+		}
+
+		if extractErr := c.extract(step, body); extractErr != nil {
+			rec.Code = code
+			rec.ErrKind = errKindOther
+			rec.Cost = time.Since(start)
+			return rec, extractErr
+		}
+
+		rec.Code = code
+		rec.ReadBytes += int64(len(body))
+	}
+
+	rec.Cost = time.Since(start)
+	return rec, nil
+}
+
+func (c *scenarioClient) runStep(step scenarioStep) (code int, body []byte, err error) {
+	url := c.render(step.URL)
+	var bodyReader *strings.Reader
+	if step.Body != "" {
+		bodyReader = strings.NewReader(c.render(step.Body))
+	} else {
+		bodyReader = strings.NewReader("")
+	}
+
+	req, err := http.NewRequest(step.Method, url, bodyReader)
+	if err != nil {
+		return 0, nil, err
+	}
+	for k, v := range step.Headers {
+		req.Header.Set(k, c.render(v))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, err
+	}
+
+	return resp.StatusCode, body, nil
+}
+
+// extract pulls variables out of a step's response body via the
+// JSONPath-lite or regexp rules declared on the step, and stashes them
+// on c.vars for use by later steps.
+func (c *scenarioClient) extract(step scenarioStep, body []byte) error {
+	for name, path := range step.ExtractJSON {
+		val, err := extractJSONPath(body, path)
+		if err != nil {
+			return fmt.Errorf("bombardier: extracting %q via %q: %s", name, path, err)
+		}
+		c.vars[name] = val
+	}
+	for name, pattern := range step.ExtractRegexp {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("bombardier: compiling regexp for %q: %s", name, err)
+		}
+		m := re.FindSubmatch(body)
+		if len(m) < 2 {
+			return fmt.Errorf("bombardier: regexp for %q did not match response", name)
+		}
+		c.vars[name] = string(m[1])
+	}
+	return nil
+}
+
+// extractJSONPath supports a small subset of JSONPath: a dot-separated
+// chain of object field names, optionally with [index] array access,
+// e.g. "data.items[0].id". It is intentionally not a full JSONPath
+// implementation, just enough to pull a single scalar out of a typical
+// REST response.
+func extractJSONPath(body []byte, path string) (string, error) {
+	var v interface{}
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&v); err != nil {
+		return "", err
+	}
+
+	for _, part := range strings.Split(path, ".") {
+		name := part
+		var index = -1
+		if idx := strings.IndexByte(part, '['); idx != -1 && strings.HasSuffix(part, "]") {
+			name = part[:idx]
+			fmt.Sscanf(part[idx+1:len(part)-1], "%d", &index)
+		}
+
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("path segment %q: not an object", name)
+		}
+		v, ok = m[name]
+		if !ok {
+			return "", fmt.Errorf("path segment %q: field not found", name)
+		}
+
+		if index >= 0 {
+			arr, ok := v.([]interface{})
+			if !ok || index >= len(arr) {
+				return "", fmt.Errorf("path segment %q: not a long-enough array", part)
+			}
+			v = arr[index]
+		}
+	}
+
+	return fmt.Sprintf("%v", v), nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+func TestExtractJSONPath(t *testing.T) {
+	body := []byte(`{
+		"id": "abc123",
+		"count": 3,
+		"data": {
+			"items": [
+				{"id": "first"},
+				{"id": "second"}
+			]
+		}
+	}`)
+
+	cases := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{name: "top level field", path: "id", want: "abc123"},
+		{name: "nested field", path: "data.items[0].id", want: "first"},
+		{name: "second array element", path: "data.items[1].id", want: "second"},
+		{name: "number field", path: "count", want: "3"},
+		{name: "missing field", path: "missing", wantErr: true},
+		{name: "index out of range", path: "data.items[5].id", wantErr: true},
+		{name: "not an object", path: "id.nested", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := extractJSONPath(body, tc.path)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("extractJSONPath(%q) = %q, nil; want error", tc.path, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractJSONPath(%q) unexpected error: %s", tc.path, err)
+			}
+			if got != tc.want {
+				t.Errorf("extractJSONPath(%q) = %q, want %q", tc.path, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExtractJSONPathInvalidBody(t *testing.T) {
+	if _, err := extractJSONPath([]byte("not json"), "id"); err == nil {
+		t.Fatal("extractJSONPath with invalid json body: expected error, got nil")
+	}
+}
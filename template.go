@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"io/ioutil"
+	mathrand "math/rand"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+)
+
+// corpus is a preloaded set of payload rows (one per line of a CSV or
+// JSONL file) that templated requests can pull from via {{pick}}, so
+// that identical payloads don't hit a cache and skew benchmark results.
+type corpus struct {
+	rows []string
+	next uint64
+}
+
+func loadCorpus(path string) (*corpus, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rows []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		rows = append(rows, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("bombardier: corpus %q has no rows", path)
+	}
+
+	return &corpus{rows: rows}, nil
+}
+
+// rotate returns the next row in sequence, wrapping around. Used by
+// --body-file-list so every worker steadily cycles through the corpus
+// instead of requesting the same row repeatedly.
+func (c *corpus) rotate() string {
+	i := atomic.AddUint64(&c.next, 1) - 1
+	return c.rows[i%uint64(len(c.rows))]
+}
+
+// pickRandom returns a uniformly random row, used by the {{pick}}
+// template helper.
+func (c *corpus) pickRandom() string {
+	return c.rows[mathrand.Intn(len(c.rows))]
+}
+
+// corpusRegistry reads each corpus file into memory only once no matter
+// how many times {{pick "file.txt"}} or --body-file-list reference it.
+// Files are read line by line into a slice; they are not memory-mapped.
+type corpusRegistry struct {
+	mu     sync.Mutex
+	byPath map[string]*corpus
+}
+
+var corpora = &corpusRegistry{byPath: make(map[string]*corpus)}
+
+func (r *corpusRegistry) get(path string) (*corpus, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if c, ok := r.byPath[path]; ok {
+		return c, nil
+	}
+	c, err := loadCorpus(path)
+	if err != nil {
+		return nil, err
+	}
+	r.byPath[path] = c
+	return c, nil
+}
+
+// seqCounter backs the {{seq}} template helper: a monotonic counter
+// shared across all requests rendered from the same template.
+type seqCounter struct {
+	n uint64
+}
+
+func (s *seqCounter) next() uint64 {
+	return atomic.AddUint64(&s.n, 1) - 1
+}
+
+func randomUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("bombardier: generating uuid: %s", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// randInt uses math/rand rather than crypto/rand: it runs on every
+// templated request in the hot path, and the randInt/seq/pick family of
+// helpers only needs to vary payloads to dodge caching, not resist
+// prediction.
+func randInt(min, max int) (int, error) {
+	if max <= min {
+		return 0, fmt.Errorf("bombardier: randInt range [%d, %d) is empty", min, max)
+	}
+	return min + mathrand.Intn(max-min), nil
+}
+
+// templateFuncs builds the {{randInt}}, {{uuid}}, {{seq}} and {{pick}}
+// helpers for one template.Template. Callers pass a seqCounter scoped to
+// that single template so {{seq}} counts independently per field.
+func templateFuncs(seq *seqCounter) template.FuncMap {
+	return template.FuncMap{
+		"randInt": randInt,
+		"uuid":    randomUUID,
+		"seq":     seq.next,
+		"pick": func(path string) (string, error) {
+			c, err := corpora.get(path)
+			if err != nil {
+				return "", err
+			}
+			return c.pickRandom(), nil
+		},
+	}
+}
+
+// requestTemplate renders a request's URL, headers and body from
+// text/template sources ahead of every call to do(), so that
+// --body-file-list style corpora and the randInt/uuid/seq/pick helpers
+// can vary each request's payload instead of sending an identical body
+// on every iteration. Each of url, body and every header gets its own
+// {{seq}} counter, so e.g. a url and a header that both use {{seq}}
+// count independently from 0 rather than splitting one shared sequence.
+type requestTemplate struct {
+	url     *template.Template
+	headers map[string]*template.Template
+	body    *template.Template
+}
+
+func newRequestTemplate(urlSrc string, headerSrcs map[string]string, bodySrc string) (*requestTemplate, error) {
+	rt := &requestTemplate{
+		headers: make(map[string]*template.Template, len(headerSrcs)),
+	}
+
+	var err error
+	if rt.url, err = template.New("url").Funcs(templateFuncs(new(seqCounter))).Parse(urlSrc); err != nil {
+		return nil, fmt.Errorf("bombardier: parsing url template: %s", err)
+	}
+	if rt.body, err = template.New("body").Funcs(templateFuncs(new(seqCounter))).Parse(bodySrc); err != nil {
+		return nil, fmt.Errorf("bombardier: parsing body template: %s", err)
+	}
+	for k, src := range headerSrcs {
+		tpl, herr := template.New(k).Funcs(templateFuncs(new(seqCounter))).Parse(src)
+		if herr != nil {
+			return nil, fmt.Errorf("bombardier: parsing header template %q: %s", k, herr)
+		}
+		rt.headers[k] = tpl
+	}
+
+	return rt, nil
+}
+
+func (rt *requestTemplate) render() (url string, headers map[string]string, body string, err error) {
+	var buf strings.Builder
+
+	if err = rt.url.Execute(&buf, nil); err != nil {
+		return "", nil, "", err
+	}
+	url = buf.String()
+
+	headers = make(map[string]string, len(rt.headers))
+	for k, tpl := range rt.headers {
+		buf.Reset()
+		if err = tpl.Execute(&buf, nil); err != nil {
+			return "", nil, "", err
+		}
+		headers[k] = buf.String()
+	}
+
+	buf.Reset()
+	if err = rt.body.Execute(&buf, nil); err != nil {
+		return "", nil, "", err
+	}
+	body = buf.String()
+
+	return url, headers, body, nil
+}
+
+// bodyFileListProducer builds a bodyStreamProducer that rotates through
+// the rows of a --body-file-list corpus, one row per request.
+func bodyFileListProducer(path string) (bodyStreamProducer, error) {
+	c, err := corpora.get(path)
+	if err != nil {
+		return nil, err
+	}
+	return func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader(c.rotate())), nil
+	}, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import "testing"
+
+func TestRandInt(t *testing.T) {
+	cases := []struct {
+		name     string
+		min, max int
+		wantErr  bool
+	}{
+		{name: "normal range", min: 0, max: 10},
+		{name: "negative range", min: -5, max: 5},
+		{name: "single value range", min: 5, max: 6},
+		{name: "empty range", min: 5, max: 5, wantErr: true},
+		{name: "inverted range", min: 10, max: 0, wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				got, err := randInt(tc.min, tc.max)
+				if tc.wantErr {
+					if err == nil {
+						t.Fatalf("randInt(%d, %d) = %d, nil; want error", tc.min, tc.max, got)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("randInt(%d, %d) unexpected error: %s", tc.min, tc.max, err)
+				}
+				if got < tc.min || got >= tc.max {
+					t.Fatalf("randInt(%d, %d) = %d, out of range", tc.min, tc.max, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCorpusRotateWraps(t *testing.T) {
+	c := &corpus{rows: []string{"a", "b", "c"}}
+
+	want := []string{"a", "b", "c", "a", "b", "c", "a"}
+	for i, w := range want {
+		if got := c.rotate(); got != w {
+			t.Fatalf("rotate() call %d = %q, want %q", i, got, w)
+		}
+	}
+}
+
+func TestCorpusRotateSingleRow(t *testing.T) {
+	c := &corpus{rows: []string{"only"}}
+	for i := 0; i < 5; i++ {
+		if got := c.rotate(); got != "only" {
+			t.Fatalf("rotate() call %d = %q, want %q", i, got, "only")
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// errKindAssertion and assertionFailedCode mark a response that failed
+// user-declared validation rather than a transport error, so it shows
+// up as its own bucket in stats instead of being folded into "error".
+const errKindAssertion = "assertion"
+const assertionFailedCode = -2
+
+// maxValidationBodyBytes bounds how much of a response body is buffered
+// for predicate evaluation. Large enough for typical JSON/text
+// responses without letting a single huge body blow up memory under
+// high QPS.
+const maxValidationBodyBytes = 1 << 20
+
+// responseView is what a predicate gets to inspect; it is built once
+// per request regardless of how many predicates are configured.
+type responseView struct {
+	body    []byte
+	headers map[string]string
+	cost    time.Duration
+}
+
+// predicate is a single success criterion declared on top of a plain
+// status-code check: body content, a JSON field, a header, or a
+// latency ceiling.
+type predicate interface {
+	check(r responseView) error
+}
+
+type bodyContainsPredicate struct{ substr string }
+
+func (p bodyContainsPredicate) check(r responseView) error {
+	if !bytes.Contains(r.body, []byte(p.substr)) {
+		return fmt.Errorf("response body does not contain %q", p.substr)
+	}
+	return nil
+}
+
+type bodyRegexpPredicate struct{ re *regexp.Regexp }
+
+func (p bodyRegexpPredicate) check(r responseView) error {
+	if !p.re.Match(r.body) {
+		return fmt.Errorf("response body does not match %q", p.re.String())
+	}
+	return nil
+}
+
+type headerPredicate struct{ name, value string }
+
+func (p headerPredicate) check(r responseView) error {
+	// Header casing isn't comparable across backends: fasthttp is run
+	// with DisableHeaderNamesNormalizing so it preserves whatever casing
+	// the server sent, while net/http canonicalizes every header name.
+	// Look the name up case-insensitively so the same predicate behaves
+	// the same against both.
+	var got string
+	var found bool
+	for k, v := range r.headers {
+		if strings.EqualFold(k, p.name) {
+			got, found = v, true
+			break
+		}
+	}
+	if !found || got != p.value {
+		return fmt.Errorf("header %q = %q, want %q", p.name, got, p.value)
+	}
+	return nil
+}
+
+type jsonFieldPredicate struct{ path, value string }
+
+func (p jsonFieldPredicate) check(r responseView) error {
+	got, err := extractJSONPath(r.body, p.path)
+	if err != nil {
+		return fmt.Errorf("evaluating json path %q: %s", p.path, err)
+	}
+	if got != p.value {
+		return fmt.Errorf("json field %q = %q, want %q", p.path, got, p.value)
+	}
+	return nil
+}
+
+type maxLatencyPredicate struct{ max time.Duration }
+
+func (p maxLatencyPredicate) check(r responseView) error {
+	if r.cost > p.max {
+		return fmt.Errorf("latency %s exceeds max %s", r.cost, p.max)
+	}
+	return nil
+}
+
+// validateResponse runs every predicate against r, stopping at (and
+// returning) the first failure.
+func validateResponse(predicates []predicate, r responseView) error {
+	for _, p := range predicates {
+		if err := p.check(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
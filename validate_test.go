@@ -0,0 +1,97 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestBodyContainsPredicate(t *testing.T) {
+	p := bodyContainsPredicate{substr: "hello"}
+
+	if err := p.check(responseView{body: []byte("oh hello world")}); err != nil {
+		t.Errorf("check() = %v, want nil", err)
+	}
+	if err := p.check(responseView{body: []byte("goodbye")}); err == nil {
+		t.Error("check() = nil, want error")
+	}
+}
+
+func TestBodyRegexpPredicate(t *testing.T) {
+	p := bodyRegexpPredicate{re: regexp.MustCompile(`^\d+$`)}
+
+	if err := p.check(responseView{body: []byte("12345")}); err != nil {
+		t.Errorf("check() = %v, want nil", err)
+	}
+	if err := p.check(responseView{body: []byte("abc")}); err == nil {
+		t.Error("check() = nil, want error")
+	}
+}
+
+func TestHeaderPredicate(t *testing.T) {
+	p := headerPredicate{name: "Content-Type", value: "application/json"}
+
+	cases := []struct {
+		name    string
+		headers map[string]string
+		wantErr bool
+	}{
+		{name: "exact case match", headers: map[string]string{"Content-Type": "application/json"}},
+		{name: "server casing from fasthttp", headers: map[string]string{"content-type": "application/json"}},
+		{name: "upper casing", headers: map[string]string{"CONTENT-TYPE": "application/json"}},
+		{name: "wrong value", headers: map[string]string{"Content-Type": "text/plain"}, wantErr: true},
+		{name: "missing header", headers: map[string]string{}, wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := p.check(responseView{headers: tc.headers})
+			if tc.wantErr && err == nil {
+				t.Error("check() = nil, want error")
+			}
+			if !tc.wantErr && err != nil {
+				t.Errorf("check() = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestJSONFieldPredicate(t *testing.T) {
+	p := jsonFieldPredicate{path: "data.id", value: "42"}
+	body := []byte(`{"data": {"id": "42"}}`)
+
+	if err := p.check(responseView{body: body}); err != nil {
+		t.Errorf("check() = %v, want nil", err)
+	}
+
+	other := jsonFieldPredicate{path: "data.id", value: "7"}
+	if err := other.check(responseView{body: body}); err == nil {
+		t.Error("check() = nil, want error")
+	}
+}
+
+func TestMaxLatencyPredicate(t *testing.T) {
+	p := maxLatencyPredicate{max: 100 * time.Millisecond}
+
+	if err := p.check(responseView{cost: 50 * time.Millisecond}); err != nil {
+		t.Errorf("check() = %v, want nil", err)
+	}
+	if err := p.check(responseView{cost: 200 * time.Millisecond}); err == nil {
+		t.Error("check() = nil, want error")
+	}
+}
+
+func TestValidateResponseStopsAtFirstFailure(t *testing.T) {
+	predicates := []predicate{
+		bodyContainsPredicate{substr: "hello"},
+		maxLatencyPredicate{max: time.Millisecond},
+	}
+
+	err := validateResponse(predicates, responseView{body: []byte("goodbye"), cost: time.Second})
+	if err == nil {
+		t.Fatal("validateResponse() = nil, want error")
+	}
+	if got := err.Error(); got != `response body does not contain "hello"` {
+		t.Errorf("validateResponse() = %q, want first failing predicate's error", got)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"golang.org/x/time/rate"
+)
+
+// wsClient implements the client interface over a WebSocket connection.
+// Unlike the HTTP clients it dials once per virtual user rather than
+// per request, and each do() measures the round-trip latency of a
+// single send/recv message pair over that connection.
+type wsClient struct {
+	conn *websocket.Conn
+
+	message []byte
+
+	rateLimiter *rate.Limiter
+	validators  []predicate
+}
+
+func newWSClient(opts *clientOpts, message []byte) (client, error) {
+	dialer := &websocket.Dialer{
+		TLSClientConfig:  opts.tlsConfig,
+		HandshakeTimeout: opts.timeout,
+	}
+
+	header := headersToHTTPHeaders(opts.headers)
+	conn, _, err := dialer.Dial(opts.url, header)
+	if err != nil {
+		return nil, err
+	}
+
+	return client(&wsClient{
+		conn:        conn,
+		message:     message,
+		rateLimiter: opts.rateLimiter,
+		validators:  opts.validators,
+	}), nil
+}
+
+func (c *wsClient) do() (rec *RequestRecord, err error) {
+	rec = acquireRequestRecord()
+
+	if c.rateLimiter != nil {
+		if werr := c.rateLimiter.Wait(context.Background()); werr != nil {
+			rec.ErrKind = errKindOther
+			return rec, werr
+		}
+	}
+
+	start := time.Now()
+	if werr := c.conn.WriteMessage(websocket.TextMessage, c.message); werr != nil {
+		rec.Cost = time.Since(start)
+		rec.Code = -1
+		rec.ErrKind = classifyErrKind(werr)
+		return rec, werr
+	}
+	rec.WriteBytes = int64(len(c.message))
+
+	_, reply, rerr := c.conn.ReadMessage()
+	rec.Cost = time.Since(start)
+	if rerr != nil {
+		rec.Code = -1
+		rec.ErrKind = classifyErrKind(rerr)
+		return rec, rerr
+	}
+
+	rec.Code = 0
+	rec.ReadBytes = int64(len(reply))
+	rec.BodySnippet = append(rec.BodySnippet, truncateBody(reply)...)
+
+	if len(c.validators) > 0 {
+		view := responseView{body: reply, cost: rec.Cost, headers: map[string]string{}}
+		if verr := validateResponse(c.validators, view); verr != nil {
+			rec.Code = assertionFailedCode
+			rec.ErrKind = errKindAssertion
+			return rec, verr
+		}
+	}
+
+	return rec, nil
+}